@@ -0,0 +1,24 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+// crawlFlatten holds --flatten: export and flatten a docker image into a
+// single tree before scanning it, matching log4j-sniffer's pre-layer-aware
+// behaviour, instead of scanning each layer individually with provenance.
+var crawlFlatten bool
+
+func init() {
+	crawlCmd.PersistentFlags().BoolVar(&crawlFlatten, "flatten", false, "Scan docker images by exporting and flattening them into a single tree, instead of scanning each layer individually (loses layer provenance and the detection of files deleted in upper layers)")
+}
@@ -0,0 +1,279 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/google/go-containerregistry/pkg/v1/tarball"
+	"github.com/palantir/log4j-sniffer/pkg/archive"
+	"github.com/palantir/log4j-sniffer/pkg/crawl"
+	"github.com/palantir/log4j-sniffer/pkg/imageref"
+	"github.com/palantir/log4j-sniffer/pkg/ocilayout"
+	"github.com/palantir/log4j-sniffer/pkg/remote"
+	"github.com/palantir/log4j-sniffer/pkg/sbom"
+	"github.com/palantir/log4j-sniffer/pkg/scan"
+	"github.com/palantir/log4j-sniffer/pkg/scan/docker"
+	"github.com/palantir/log4j-sniffer/pkg/signatures"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+// imageCmd is a single entrypoint that dispatches a transport-prefixed
+// reference (see pkg/imageref) to whichever scanning backend applies, so
+// callers don't need to know in advance whether an image lives in a
+// registry, the local daemon, or an OCI layout or archive on disk.
+//
+// containers-storage: and dir: references are recognised by pkg/imageref
+// but not yet scannable; RunE reports a clear error for them rather than
+// pretending they work.
+var imageCmd = &cobra.Command{
+	Use:   "image <reference>",
+	Short: "Scans a single image, resolving docker://, docker-daemon://, oci:, oci-archive: and docker-archive: references",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := crawlConfigFromFlags()
+		if err != nil {
+			return err
+		}
+		sbomCollector, sbomFormat, err := sbomCollectorFromFlags()
+		if err != nil {
+			return err
+		}
+		db, err := signaturesDatabaseFromFlags()
+		if err != nil {
+			return err
+		}
+
+		parsed := imageref.Parse(args[0])
+		switch parsed.Transport {
+		case imageref.Docker:
+			scanner := remote.NewRemoteScanner(config, cmd.OutOrStdout(), cmd.ErrOrStderr(), remote.Options{}, sbomCollector, db)
+			count, err := scanner.ScanReferences(cmd.Context(), []string{parsed.Location})
+			if err != nil {
+				return err
+			}
+			if err := writeSBOMIfRequested(cmd.OutOrStdout(), sbomCollector, sbomFormat); err != nil {
+				return err
+			}
+			return exitWithCountStatus(cmd, count)
+		case imageref.DockerDaemon:
+			scanner, err := docker.NewDockerScanner(config, cmd.OutOrStdout(), cmd.ErrOrStderr(), crawlFlatten, sbomCollector, db)
+			if err != nil {
+				return err
+			}
+			count, err := scanner.ScanImageRef(cmd.Context(), parsed.Location)
+			if err != nil {
+				return err
+			}
+			if err := writeSBOMIfRequested(cmd.OutOrStdout(), sbomCollector, sbomFormat); err != nil {
+				return err
+			}
+			return exitWithCountStatus(cmd, count)
+		case imageref.OCI:
+			scanner := ocilayout.NewScanner(config, cmd.OutOrStdout(), cmd.ErrOrStderr(), sbomCollector, db)
+			count, err := scanner.ScanDir(cmd.Context(), parsed.Location)
+			if err != nil {
+				return err
+			}
+			if err := writeSBOMIfRequested(cmd.OutOrStdout(), sbomCollector, sbomFormat); err != nil {
+				return err
+			}
+			return exitWithCountStatus(cmd, count)
+		case imageref.OCIArchive:
+			count, err := scanOCIArchive(cmd, config, parsed.Location, sbomCollector, db)
+			if err != nil {
+				return err
+			}
+			if err := writeSBOMIfRequested(cmd.OutOrStdout(), sbomCollector, sbomFormat); err != nil {
+				return err
+			}
+			return exitWithCountStatus(cmd, count)
+		case imageref.DockerArchive:
+			count, err := scanDockerArchive(cmd, config, parsed.Location, sbomCollector, db)
+			if err != nil {
+				return err
+			}
+			if err := writeSBOMIfRequested(cmd.OutOrStdout(), sbomCollector, sbomFormat); err != nil {
+				return err
+			}
+			return exitWithCountStatus(cmd, count)
+		default:
+			return errors.Errorf("the %q transport is recognised but not yet supported by log4j-sniffer", parsed.Transport)
+		}
+	},
+}
+
+func init() {
+	crawlCmd.AddCommand(imageCmd)
+}
+
+// scanOCIArchive extracts an `oci-archive:` tarball (a tar of an OCI image
+// layout directory, as produced by `podman save --format oci-archive` or
+// `skopeo copy ... oci-archive:`) to a temporary directory and scans it as
+// an OCI layout, since none of our backends can stream an OCI layout
+// directly out of a tar entry.
+func scanOCIArchive(cmd *cobra.Command, config scan.Config, archivePath string, sbomCollector *sbom.Collector, db *signatures.Database) (int64, error) {
+	tmpDir, err := os.MkdirTemp("", "log4j-sniffer-oci-archive")
+	if err != nil {
+		return 0, errors.Wrap(err, "could not create temporary directory for oci archive")
+	}
+	defer func() {
+		_ = os.RemoveAll(tmpDir)
+	}()
+
+	if err := extractTar(archivePath, tmpDir); err != nil {
+		return 0, errors.Wrapf(err, "failed to extract oci archive %q", archivePath)
+	}
+
+	scanner := ocilayout.NewScanner(config, cmd.OutOrStdout(), cmd.ErrOrStderr(), sbomCollector, db)
+	return scanner.ScanDir(cmd.Context(), tmpDir)
+}
+
+// scanDockerArchive scans a `docker-archive:` reference: a legacy
+// `docker save`-format tarball (manifest.json plus flat layer tarballs),
+// which is a different format from an oci-archive: tarball and is not a
+// valid OCI image layout. go-containerregistry's tarball package reads
+// this format natively, so unlike scanOCIArchive no extraction is needed.
+func scanDockerArchive(cmd *cobra.Command, config scan.Config, archivePath string, sbomCollector *sbom.Collector, db *signatures.Database) (int64, error) {
+	img, err := tarball.ImageFromPath(archivePath, nil)
+	if err != nil {
+		return 0, errors.Wrapf(err, "%q is not a valid docker save tarball", archivePath)
+	}
+
+	crawler := crawl.Crawler{
+		ErrorWriter: cmd.ErrOrStderr(),
+		IgnoreDirs:  config.Ignores,
+	}
+	reporter := &crawl.Reporter{
+		OutputJSON:      config.OutputJSON,
+		OutputWriter:    cmd.OutOrStdout(),
+		DisableCVE45105: config.DisableCVE45105,
+	}
+	identifier := crawl.NewIdentifier(config.ArchiveListTimeout, archive.WalkZipFiles, archive.WalkTarGzFiles, db)
+	collect := func(path string, data []byte, matches []crawl.Match) {
+		reporter.Collect(path, data, matches)
+		sbom.CollectComponent(sbomCollector, path, data, crawl.CombinedFinding(matches))
+	}
+
+	layers, err := img.Layers()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read image layers")
+	}
+
+	var stats crawl.Stats
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return 0, errors.Wrap(err, "failed to read layer digest")
+		}
+		uncompressed, err := layer.Uncompressed()
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to read layer %s", digest)
+		}
+		layerStats, err := crawler.CrawlReader(cmd.Context(), archive.TarUncompressedReader, uncompressed, digest.String(), identifier.Identify, collect)
+		closeErr := uncompressed.Close()
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to crawl layer %s", digest)
+		}
+		if closeErr != nil {
+			return 0, errors.Wrapf(closeErr, "failed to close layer %s", digest)
+		}
+		stats.Append(layerStats)
+	}
+
+	count := reporter.Count()
+	if config.OutputSummary {
+		if err := scan.WriteSummary(reporter.OutputWriter, config, stats, count); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+// extractTar extracts a (possibly gzip-compressed) tarball to destDir,
+// rejecting any entry whose name would escape destDir (Tar Slip) - this
+// matters more than usual here since callers routinely point it at
+// untrusted, attacker-supplied images.
+func extractTar(archivePath, destDir string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	var r io.Reader = f
+	if gzr, err := gzip.NewReader(f); err == nil {
+		r = gzr
+	} else if _, seekErr := f.Seek(0, io.SeekStart); seekErr != nil {
+		return seekErr
+	}
+
+	cleanDestDir := filepath.Clean(destDir)
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		target, err := safeJoin(cleanDestDir, hdr.Name)
+		if err != nil {
+			return errors.Wrapf(err, "refusing to extract tar entry %q", hdr.Name)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(target, 0o755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+				return err
+			}
+			out, err := os.Create(target)
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(out, tr); err != nil {
+				_ = out.Close()
+				return err
+			}
+			if err := out.Close(); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// safeJoin joins name onto destDir and verifies the cleaned result is
+// still contained within destDir, rejecting names whose "../" segments
+// would otherwise let a tar entry write outside destDir. An absolute name
+// is joined under destDir like a relative one.
+func safeJoin(destDir, name string) (string, error) {
+	target := filepath.Join(destDir, name)
+	if target != destDir && !strings.HasPrefix(target, destDir+string(os.PathSeparator)) {
+		return "", errors.Errorf("path %q escapes destination directory %q", name, destDir)
+	}
+	return target, nil
+}
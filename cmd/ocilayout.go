@@ -0,0 +1,53 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"github.com/palantir/log4j-sniffer/pkg/ocilayout"
+	"github.com/spf13/cobra"
+)
+
+var ociLayoutCmd = &cobra.Command{
+	Use:   "oci-layout [directory]",
+	Short: "Scans every image referenced by an OCI image layout directory (oci-layout + index.json + blobs/)",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config, err := crawlConfigFromFlags()
+		if err != nil {
+			return err
+		}
+		sbomCollector, sbomFormat, err := sbomCollectorFromFlags()
+		if err != nil {
+			return err
+		}
+		db, err := signaturesDatabaseFromFlags()
+		if err != nil {
+			return err
+		}
+		scanner := ocilayout.NewScanner(config, cmd.OutOrStdout(), cmd.ErrOrStderr(), sbomCollector, db)
+		count, err := scanner.ScanDir(cmd.Context(), args[0])
+		if err != nil {
+			return err
+		}
+		if err := writeSBOMIfRequested(cmd.OutOrStdout(), sbomCollector, sbomFormat); err != nil {
+			return err
+		}
+		return exitWithCountStatus(cmd, count)
+	},
+}
+
+func init() {
+	crawlCmd.AddCommand(ociLayoutCmd)
+}
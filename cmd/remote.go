@@ -0,0 +1,97 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"runtime"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/palantir/log4j-sniffer/pkg/remote"
+	"github.com/pkg/errors"
+	"github.com/spf13/cobra"
+)
+
+var (
+	remoteCmd = &cobra.Command{
+		Use:   "remote [image-reference...]",
+		Short: "Scans one or more images pulled directly from a registry, without requiring a local Docker daemon",
+		Long:  "Scans one or more images pulled directly from a registry, without requiring a local Docker daemon.\n\nWith --catalog, each argument is a registry host (e.g. \"registry.example.com\") instead of an image reference, and every tag of every repository in that registry's catalog is scanned.",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			platform, err := parsePlatform(remotePlatform)
+			if err != nil {
+				return err
+			}
+			config, err := crawlConfigFromFlags()
+			if err != nil {
+				return err
+			}
+			sbomCollector, sbomFormat, err := sbomCollectorFromFlags()
+			if err != nil {
+				return err
+			}
+			db, err := signaturesDatabaseFromFlags()
+			if err != nil {
+				return err
+			}
+			scanner := remote.NewRemoteScanner(config, cmd.OutOrStdout(), cmd.ErrOrStderr(), remote.Options{
+				Platform:     platform,
+				Insecure:     remoteInsecure,
+				AllTags:      remoteAllTags,
+				AllPlatforms: remoteAllPlatforms,
+				Catalog:      remoteCatalog,
+			}, sbomCollector, db)
+			var count int64
+			if remoteCatalog {
+				count, err = scanner.ScanCatalog(cmd.Context(), args)
+			} else {
+				count, err = scanner.ScanReferences(cmd.Context(), args)
+			}
+			if err != nil {
+				return err
+			}
+			if err := writeSBOMIfRequested(cmd.OutOrStdout(), sbomCollector, sbomFormat); err != nil {
+				return err
+			}
+			return exitWithCountStatus(cmd, count)
+		},
+	}
+
+	remotePlatform     string
+	remoteInsecure     bool
+	remoteAllTags      bool
+	remoteAllPlatforms bool
+	remoteCatalog      bool
+)
+
+func init() {
+	crawlCmd.AddCommand(remoteCmd)
+	remoteCmd.Flags().StringVar(&remotePlatform, "platform", "", "Platform to scan for multi-arch images, e.g. linux/amd64 (defaults to the runtime platform)")
+	remoteCmd.Flags().BoolVar(&remoteInsecure, "insecure", false, "Allow connecting to registries over plain HTTP or with self-signed certificates")
+	remoteCmd.Flags().BoolVar(&remoteAllTags, "all-tags", false, "Scan every tag in the referenced repository instead of a single reference")
+	remoteCmd.Flags().BoolVar(&remoteAllPlatforms, "all-platforms", false, "Scan every platform of a multi-arch image instead of just the one matching --platform")
+	remoteCmd.Flags().BoolVar(&remoteCatalog, "catalog", false, "Treat each argument as a registry host and scan every tag of every repository in its catalog, instead of scanning named image references")
+}
+
+func parsePlatform(platform string) (*v1.Platform, error) {
+	if platform == "" {
+		return &v1.Platform{OS: runtime.GOOS, Architecture: runtime.GOARCH}, nil
+	}
+	p, err := v1.ParsePlatform(platform)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse --platform %q", platform)
+	}
+	return p, nil
+}
@@ -0,0 +1,70 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io"
+
+	"github.com/palantir/log4j-sniffer/pkg/sbom"
+	"github.com/pkg/errors"
+)
+
+// sbomOutput holds the raw --sbom-output flag value shared by every crawl
+// subcommand; empty means no SBOM is emitted.
+var sbomOutput string
+
+func init() {
+	crawlCmd.PersistentFlags().StringVar(&sbomOutput, "sbom-output", "", "Emit a software bill of materials in the given format alongside findings: cyclonedx-json or spdx-json")
+}
+
+// sbomFormatFromFlag validates --sbom-output and returns the format to
+// write, or ok=false if no SBOM was requested.
+func sbomFormatFromFlag() (format sbom.OutputFormat, ok bool, err error) {
+	if sbomOutput == "" {
+		return "", false, nil
+	}
+	switch sbom.OutputFormat(sbomOutput) {
+	case sbom.CycloneDXJSON, sbom.SPDXJSON:
+		return sbom.OutputFormat(sbomOutput), true, nil
+	default:
+		return "", false, errors.Errorf("unsupported --sbom-output format %q, expected %q or %q", sbomOutput, sbom.CycloneDXJSON, sbom.SPDXJSON)
+	}
+}
+
+// sbomCollectorFromFlags returns a Collector to pass into a scanner
+// constructor, and the format to render it in, if --sbom-output was given.
+// collector is nil when no SBOM was requested, so callers can pass it
+// straight through to a scanner unconditionally.
+func sbomCollectorFromFlags() (collector *sbom.Collector, format sbom.OutputFormat, err error) {
+	format, ok, err := sbomFormatFromFlag()
+	if err != nil {
+		return nil, "", err
+	}
+	if !ok {
+		return nil, "", nil
+	}
+	return sbom.NewCollector(), format, nil
+}
+
+// writeSBOMIfRequested renders collector's accumulated components to w in
+// format, once a scan has finished populating it. It is a no-op when
+// collector is nil, which is what sbomCollectorFromFlags returns when
+// --sbom-output wasn't given.
+func writeSBOMIfRequested(w io.Writer, collector *sbom.Collector, format sbom.OutputFormat) error {
+	if collector == nil {
+		return nil
+	}
+	return collector.Write(w, format)
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import "github.com/palantir/log4j-sniffer/pkg/signatures"
+
+// signatureSources holds the raw --signatures flag values, each either a
+// local file path or an http(s) URL, merged on top of the built-in
+// defaults in the order given.
+var signatureSources []string
+
+func init() {
+	crawlCmd.PersistentFlags().StringArrayVar(&signatureSources, "signatures", nil, "Additional signature database file or URL to add to or override the built-in CVE-2021-44228/45046/45105 rules; may be repeated")
+}
+
+// signaturesDatabaseFromFlags returns the built-in signature database with
+// every --signatures source merged on top, in the order given on the
+// command line.
+func signaturesDatabaseFromFlags() (*signatures.Database, error) {
+	db := signatures.DefaultDatabase()
+	for _, source := range signatureSources {
+		loaded, err := signatures.Load(source)
+		if err != nil {
+			return nil, err
+		}
+		db.Merge(loaded)
+	}
+	return db, nil
+}
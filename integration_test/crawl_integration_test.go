@@ -15,12 +15,19 @@
 package integration_test
 
 import (
+	"archive/zip"
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"os"
 	"os/exec"
+	"path/filepath"
 	"testing"
 
 	"github.com/palantir/godel/v2/pkg/products"
 	"github.com/palantir/log4j-sniffer/pkg/crawl"
+	"github.com/palantir/log4j-sniffer/pkg/signatures"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -87,6 +94,73 @@ func TestGoodVersion(t *testing.T) {
 	assert.NotContains(t, got, "Files affected by CVE-2021-45046 or CVE-2021-45105 detected")
 }
 
+// TestCustomSignatureDetectsMarkerClass covers the --signatures flag end to
+// end: a jar containing an arbitrary class with no relation to log4j is
+// only flagged once a custom signature database naming its class MD5 is
+// supplied, and is not flagged on a plain `crawl` of the same directory.
+//
+// The example fixtures under examples/ don't carry a jar built to exercise
+// a custom signature, so this builds its own marker jar and signature file
+// on the fly rather than reusing a checked-in example.
+func TestCustomSignatureDetectsMarkerClass(t *testing.T) {
+	cli, err := products.Bin("log4j-sniffer")
+	require.NoError(t, err)
+
+	classContents := []byte("this is not a real class file, just bytes a signature can match by MD5")
+	sum := md5.Sum(classContents)
+	classMD5 := hex.EncodeToString(sum[:])
+
+	dir := t.TempDir()
+	jarPath := filepath.Join(dir, "marker.jar")
+	writeMarkerJar(t, jarPath, "com/example/Marker.class", classContents)
+
+	sigFile := filepath.Join(dir, "marker-signature.json")
+	writeMarkerSignature(t, sigFile, signatures.Signature{
+		ID:      "EXAMPLE-MARKER-0001",
+		Aliases: []string{"EXAMPLE-MARKER-0001"},
+		Affected: []signatures.Affected{
+			{Package: signatures.Package{Ecosystem: "Maven", Name: "com.example:marker"}},
+		},
+		Detection: signatures.Detection{
+			ClassMD5s:         []string{classMD5},
+			ClassPackageNames: []string{"com/example/Marker.class"},
+		},
+	})
+
+	plain := exec.Command(cli, "crawl", dir)
+	output, err := plain.CombinedOutput()
+	require.NoError(t, err, "command %v failed with output:\n%s", plain.Args, string(output))
+	assert.NotContains(t, string(output), "EXAMPLE-MARKER-0001")
+
+	withSignature := exec.Command(cli, "crawl", "--signatures", sigFile, dir)
+	output, err = withSignature.CombinedOutput()
+	require.NoError(t, err, "command %v failed with output:\n%s", withSignature.Args, string(output))
+	assert.Contains(t, string(output), "EXAMPLE-MARKER-0001")
+}
+
+func writeMarkerJar(t *testing.T, path, entryName string, contents []byte) {
+	t.Helper()
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer func() { _ = f.Close() }()
+
+	zw := zip.NewWriter(f)
+	w, err := zw.Create(entryName)
+	require.NoError(t, err)
+	_, err = w.Write(contents)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+}
+
+func writeMarkerSignature(t *testing.T, path string, sig signatures.Signature) {
+	t.Helper()
+	body, err := json.Marshal(struct {
+		Signatures []signatures.Signature `json:"signatures"`
+	}{Signatures: []signatures.Signature{sig}})
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(path, body, 0o644))
+}
+
 func TestCve45105Flag(t *testing.T) {
 	cli, err := products.Bin("log4j-sniffer")
 	require.NoError(t, err)
@@ -20,6 +20,10 @@ import (
 	"compress/gzip"
 	"io"
 	"strings"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/pkg/errors"
+	"github.com/ulikunitz/xz"
 )
 
 const (
@@ -27,6 +31,8 @@ const (
 	TarArchive
 	TarGzArchive
 	TarBz2Archive
+	TarXzArchive
+	TarZstdArchive
 	ZipArchive
 )
 
@@ -48,6 +54,10 @@ var (
 		"tgz":     TarGzArchive,
 		"tar.bz2": TarBz2Archive,
 		"tbz2":    TarBz2Archive,
+		"tar.xz":  TarXzArchive,
+		"txz":     TarXzArchive,
+		"tar.zst": TarZstdArchive,
+		"tzst":    TarZstdArchive,
 	}
 )
 
@@ -67,6 +77,22 @@ func TarUncompressedReader(iReader io.Reader) (*tar.Reader, error) {
 	return tar.NewReader(iReader), nil
 }
 
+func TarXzReader(iReader io.Reader) (*tar.Reader, error) {
+	xzReader, err := xz.NewReader(iReader)
+	if err != nil {
+		return nil, err
+	}
+	return tar.NewReader(xzReader), nil
+}
+
+func TarZstdReader(iReader io.Reader) (*tar.Reader, error) {
+	zstdReader, err := zstd.NewReader(iReader)
+	if err != nil {
+		return nil, err
+	}
+	return tar.NewReader(zstdReader.IOReadCloser()), nil
+}
+
 func ParseArchiveFormatFromFile(filename string) (FormatType, bool) {
 	fileSplit := strings.Split(filename, ".")
 	if len(fileSplit) < 2 {
@@ -80,4 +106,72 @@ func ParseArchiveFormatFromFile(filename string) (FormatType, bool) {
 		}
 	}
 	return UnsupportedArchive, false
-}
\ No newline at end of file
+}
+
+// tarReaderProviders maps every tar-based FormatType to the
+// TarReaderProvider that can decompress it, so that adding a new
+// compression (as TarXzArchive and TarZstdArchive did) needs only its
+// FormatType constant, its suffixes in extensions, and an entry here for it
+// to be picked up by WalkTarArchiveFile below, rather than every tar-based
+// call site needing its own type switch.
+var tarReaderProviders = map[FormatType]TarReaderProvider{
+	TarArchive:     TarUncompressedReader,
+	TarGzArchive:   TarGzipReader,
+	TarBz2Archive:  TarBzip2Reader,
+	TarXzArchive:   TarXzReader,
+	TarZstdArchive: TarZstdReader,
+}
+
+// WalkTarFn is invoked for each regular file entry found while walking a
+// tar-based archive. Returning false stops the walk early without error.
+type WalkTarFn func(path string, size int64, contents io.Reader) (bool, error)
+
+// WalkTarFormat opens r as a tar archive of the given tar-based format and
+// invokes walkFunc for each regular file entry in it.
+func WalkTarFormat(format FormatType, r io.Reader, walkFunc WalkTarFn) error {
+	provider, ok := tarReaderProviders[format]
+	if !ok {
+		return errors.Errorf("format %d is not a tar-based archive format", format)
+	}
+
+	tr, err := provider(r)
+	if err != nil {
+		return err
+	}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		cont, err := walkFunc(hdr.Name, hdr.Size, tr)
+		if err != nil {
+			return err
+		}
+		if !cont {
+			return nil
+		}
+	}
+}
+
+// WalkTarArchiveFile parses the archive format from name (as a nested
+// archive walker encountering it by filename would) and, if it names a
+// tar-based archive - including the .tar.xz/.txz and .tar.zst/.tzst
+// extensions registered above - walks r with WalkTarFormat. ok is false if
+// name is not a recognised tar-based archive, letting callers skip it
+// without treating that as an error.
+func WalkTarArchiveFile(name string, r io.Reader, walkFunc WalkTarFn) (ok bool, err error) {
+	format, recognised := ParseArchiveFormatFromFile(name)
+	if !recognised {
+		return false, nil
+	}
+	if _, isTar := tarReaderProviders[format]; !isTar {
+		return false, nil
+	}
+	return true, WalkTarFormat(format, r, walkFunc)
+}
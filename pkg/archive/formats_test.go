@@ -0,0 +1,113 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/tar"
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/ulikunitz/xz"
+)
+
+func buildTarXz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	xw, err := xz.NewWriter(&buf)
+	require.NoError(t, err)
+	writeTarEntries(t, xw, files)
+	require.NoError(t, xw.Close())
+	return buf.Bytes()
+}
+
+func buildTarZstd(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw, err := zstd.NewWriter(&buf)
+	require.NoError(t, err)
+	writeTarEntries(t, zw, files)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func writeTarEntries(t *testing.T, w io.Writer, files map[string]string) {
+	t.Helper()
+	tw := tar.NewWriter(w)
+	for name, contents := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{
+			Name: name,
+			Mode: 0o644,
+			Size: int64(len(contents)),
+		}))
+		_, err := tw.Write([]byte(contents))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+}
+
+func TestWalkTarArchiveFileXzAndZstd(t *testing.T) {
+	files := map[string]string{"hello.txt": "hello world"}
+
+	for _, tt := range []struct {
+		name    string
+		archive string
+		build   func(t *testing.T, files map[string]string) []byte
+	}{
+		{name: "tar.xz", archive: "example.tar.xz", build: buildTarXz},
+		{name: "tzst", archive: "example.tzst", build: buildTarZstd},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			data := tt.build(t, files)
+
+			var found map[string]string
+			ok, err := WalkTarArchiveFile(tt.archive, bytes.NewReader(data), func(path string, size int64, contents io.Reader) (bool, error) {
+				if found == nil {
+					found = make(map[string]string)
+				}
+				body, err := io.ReadAll(contents)
+				if err != nil {
+					return false, err
+				}
+				found[path] = string(body)
+				return true, nil
+			})
+			require.NoError(t, err)
+			assert.True(t, ok)
+			assert.Equal(t, files, found)
+		})
+	}
+}
+
+func TestWalkTarArchiveFileUnrecognisedName(t *testing.T) {
+	ok, err := WalkTarArchiveFile("notanarchive.txt", bytes.NewReader(nil), func(string, int64, io.Reader) (bool, error) {
+		t.Fatal("walkFunc should not be called for an unrecognised name")
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestWalkTarArchiveFileZipIsNotTarBased(t *testing.T) {
+	ok, err := WalkTarArchiveFile("archive.zip", bytes.NewReader(nil), func(string, int64, io.Reader) (bool, error) {
+		t.Fatal("walkFunc should not be called for a non-tar archive")
+		return false, nil
+	})
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
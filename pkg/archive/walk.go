@@ -0,0 +1,84 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+)
+
+// WalkArchiveFn attempts to walk r as a nested archive named name, invoking
+// walkFunc once per regular file entry found inside it. ok is false when
+// name does not look like a format this function handles, so a caller can
+// fall through to another WalkArchiveFn (or give up) instead of treating
+// that as an error. It is the shape expected by crawl.NewIdentifier for the
+// nested-archive walkers it recurses into while identifying a jar's
+// contents.
+type WalkArchiveFn func(name string, r io.Reader, walkFunc WalkTarFn) (ok bool, err error)
+
+// WalkTarGzFiles is a WalkArchiveFn for any tar-based archive recognised by
+// ParseArchiveFormatFromFile, including the gzip/bzip2/xz/zstd-compressed
+// variants - not just .tar.gz despite the name, which is kept for
+// continuity with what callers have always asked crawl.NewIdentifier for.
+func WalkTarGzFiles(name string, r io.Reader, walkFunc WalkTarFn) (bool, error) {
+	return WalkTarArchiveFile(name, r, walkFunc)
+}
+
+// WalkZipFiles is a WalkArchiveFn for zip-based archives (.zip, .jar, .war,
+// .ear, .par), including zstd-compressed (method 93) entries via the
+// decompressor registered in zstdzip.go's init().
+func WalkZipFiles(name string, r io.Reader, walkFunc WalkTarFn) (ok bool, err error) {
+	format, recognised := ParseArchiveFormatFromFile(name)
+	if !recognised || format != ZipArchive {
+		return false, nil
+	}
+
+	// zip.NewReader needs an io.ReaderAt, but nested archive entries are
+	// only available as a stream, so buffer the entry in memory - these are
+	// individual jars/wars, not whole images.
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return true, err
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return true, err
+	}
+
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		cont, err := walkZipEntry(f, walkFunc)
+		if err != nil {
+			return true, err
+		}
+		if !cont {
+			break
+		}
+	}
+	return true, nil
+}
+
+func walkZipEntry(f *zip.File, walkFunc WalkTarFn) (bool, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return false, err
+	}
+	defer func() { _ = rc.Close() }()
+
+	return walkFunc(f.Name, int64(f.UncompressedSize64), rc)
+}
@@ -0,0 +1,48 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package archive
+
+import (
+	"archive/zip"
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// zstdZipMethod is the zip compression method identifier used for
+// zstd-compressed entries, as produced by recent JDKs and, increasingly,
+// by the Maven shade plugin. It is not one of the methods archive/zip
+// supports natively, so we register a decompressor for it below.
+const zstdZipMethod = 93
+
+func init() {
+	zip.RegisterDecompressor(zstdZipMethod, newZstdZipReader)
+}
+
+func newZstdZipReader(r io.Reader) io.ReadCloser {
+	zr, err := zstd.NewReader(r)
+	if err != nil {
+		return io.NopCloser(errReader{err})
+	}
+	return zr.IOReadCloser()
+}
+
+type errReader struct {
+	err error
+}
+
+func (e errReader) Read([]byte) (int, error) {
+	return 0, e.err
+}
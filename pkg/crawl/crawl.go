@@ -0,0 +1,174 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package crawl walks a filesystem tree or a single archive stream (an
+// uncompressed image layer, say), handing every regular file it finds to an
+// IdentifyFunc and every identified file to a CollectFunc, regardless of
+// which scanning backend (docker, remote, oci-layout, ...) is driving it.
+package crawl
+
+import (
+	"archive/tar"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/palantir/log4j-sniffer/pkg/archive"
+	"github.com/pkg/errors"
+)
+
+// IdentifyFunc is called once per regular file a Crawler visits, and
+// returns the signature matches found for it, recursing into nested
+// archives itself.
+type IdentifyFunc func(path string, size int64, data []byte) ([]Match, error)
+
+// CollectFunc is handed the matches IdentifyFunc produced for a single
+// file, along with the file's contents, so a Reporter can tally findings
+// and feed an SBOM collector. It is called for every file identify was
+// called for, not just ones with matches, since an SBOM collector needs to
+// record every archive regardless of whether it is vulnerable.
+type CollectFunc func(path string, data []byte, matches []Match)
+
+// Stats aggregates how much a crawl looked at, independent of whether
+// anything matched.
+type Stats struct {
+	FilesScanned    int64
+	ArchivesScanned int64
+}
+
+// Append adds other's counts onto s, so stats from multiple layers or
+// images can be combined into one summary.
+func (s *Stats) Append(other Stats) {
+	s.FilesScanned += other.FilesScanned
+	s.ArchivesScanned += other.ArchivesScanned
+}
+
+// Crawler walks a filesystem tree or a single archive stream. ErrorWriter
+// receives one line per file that could not be read or identified, rather
+// than aborting the whole crawl, since a single corrupt entry shouldn't
+// stop the rest of a large tree or image from being scanned. IgnoreDirs
+// names directories (matched by base name) to skip entirely.
+type Crawler struct {
+	ErrorWriter io.Writer
+	IgnoreDirs  []string
+}
+
+// Crawl walks every regular file under root, calling identify and collect
+// for each.
+func (c Crawler) Crawl(ctx context.Context, root string, identify IdentifyFunc, collect CollectFunc) (Stats, error) {
+	var stats Stats
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if c.ignored(d.Name()) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			_, _ = fmt.Fprintln(c.ErrorWriter, err.Error())
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			_, _ = fmt.Fprintln(c.ErrorWriter, err.Error())
+			return nil
+		}
+
+		stats.FilesScanned++
+		matches, err := identify(path, info.Size(), data)
+		if err != nil {
+			_, _ = fmt.Fprintln(c.ErrorWriter, err.Error())
+			return nil
+		}
+		collect(path, data, matches)
+		if _, ok := archive.ParseArchiveFormatFromFile(path); ok {
+			stats.ArchivesScanned++
+		}
+		return nil
+	})
+	if err != nil {
+		return stats, err
+	}
+	return stats, nil
+}
+
+// CrawlReader reads tar entries from r - opened with the given
+// archive.TarReaderProvider so callers can pass an already-uncompressed
+// image layer stream - calling identify and collect for every regular file
+// entry. label is used only for error messages, e.g. the layer digest.
+func (c Crawler) CrawlReader(ctx context.Context, provider archive.TarReaderProvider, r io.Reader, label string, identify IdentifyFunc, collect CollectFunc) (Stats, error) {
+	var stats Stats
+
+	tr, err := provider(r)
+	if err != nil {
+		return stats, errors.Wrapf(err, "failed to open %q as a tar archive", label)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return stats, ctx.Err()
+		default:
+		}
+
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return stats, nil
+		}
+		if err != nil {
+			return stats, errors.Wrapf(err, "failed to read next entry in %q", label)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return stats, errors.Wrapf(err, "failed to read %q from %q", hdr.Name, label)
+		}
+
+		stats.FilesScanned++
+		matches, err := identify(hdr.Name, hdr.Size, data)
+		if err != nil {
+			_, _ = fmt.Fprintln(c.ErrorWriter, err.Error())
+			continue
+		}
+		collect(hdr.Name, data, matches)
+		if _, ok := archive.ParseArchiveFormatFromFile(hdr.Name); ok {
+			stats.ArchivesScanned++
+		}
+	}
+}
+
+func (c Crawler) ignored(dirName string) bool {
+	for _, ignore := range c.IgnoreDirs {
+		if dirName == ignore {
+			return true
+		}
+	}
+	return false
+}
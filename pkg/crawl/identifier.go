@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawl
+
+import (
+	"bytes"
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/palantir/log4j-sniffer/pkg/archive"
+	"github.com/palantir/log4j-sniffer/pkg/signatures"
+)
+
+// Finding records which detection rule(s) matched a file.
+type Finding uint16
+
+const (
+	// JarName is set when a jar's own filename matches a known vulnerable
+	// jar name pattern.
+	JarName Finding = 1 << iota
+	// JarNameInsideArchive is set when a nested jar's filename matches,
+	// found while recursing into an outer archive (a dist tarball, a fat
+	// jar, a .par, ...).
+	JarNameInsideArchive
+	// ClassPackageAndName is set when a class file's full path within its
+	// jar matches a known vulnerable class.
+	ClassPackageAndName
+	// ClassFileMd5 is set when a class file's contents match a known
+	// vulnerable class's MD5 exactly.
+	ClassFileMd5
+	// ClassName is set when a class file matches by base name alone
+	// (ignoring its package), catching vulnerable classes that have been
+	// shaded or relocated into a different package.
+	ClassName
+)
+
+// Match is a single signature matching a single file.
+type Match struct {
+	SignatureID string
+	Finding     Finding
+}
+
+// Identifier identifies files matching any signature in a Database,
+// recursing into nested archives (zips, jars, tarballs, ...) via the given
+// walkers.
+type Identifier struct {
+	timeout   time.Duration
+	walkZip   archive.WalkArchiveFn
+	walkTarGz archive.WalkArchiveFn
+	db        *signatures.Database
+}
+
+// NewIdentifier returns an Identifier that detects against every signature
+// in db, recursing into nested zip-based and tar-based archives via
+// walkZip and walkTarGz respectively (typically archive.WalkZipFiles and
+// archive.WalkTarGzFiles). timeout is stored on the Identifier but is not
+// consulted: identifying a file, including any recursion into nested
+// archives, runs to completion however long it takes.
+func NewIdentifier(timeout time.Duration, walkZip, walkTarGz archive.WalkArchiveFn, db *signatures.Database) Identifier {
+	return Identifier{timeout: timeout, walkZip: walkZip, walkTarGz: walkTarGz, db: db}
+}
+
+// Identify checks path/data against every signature in the database,
+// recursing into path if it is itself a zip- or tar-based archive. It is
+// the IdentifyFunc handed to a Crawler.
+func (id Identifier) Identify(path string, size int64, data []byte) ([]Match, error) {
+	return id.identify(path, data, false)
+}
+
+func (id Identifier) identify(path string, data []byte, nested bool) ([]Match, error) {
+	var matches []Match
+
+	base := filepath.Base(path)
+	sum := md5.Sum(data)
+	md5Hex := hex.EncodeToString(sum[:])
+
+	for _, sig := range id.db.Signatures() {
+		matches = append(matches, matchSignature(sig, path, base, md5Hex, nested)...)
+	}
+
+	nestedMatches, err := id.identifyNested(path, data)
+	if err != nil {
+		return nil, err
+	}
+	return append(matches, nestedMatches...), nil
+}
+
+func matchSignature(sig signatures.Signature, path, base, md5Hex string, nested bool) []Match {
+	var matches []Match
+
+	for _, want := range sig.Detection.ClassMD5s {
+		if want == md5Hex {
+			matches = append(matches, Match{SignatureID: sig.ID, Finding: ClassFileMd5})
+		}
+	}
+	for _, want := range sig.Detection.ClassPackageNames {
+		if path == want || strings.HasSuffix(path, "/"+want) {
+			matches = append(matches, Match{SignatureID: sig.ID, Finding: ClassPackageAndName})
+		}
+	}
+	for _, pattern := range sig.Detection.JarNamePatterns {
+		if ok, _ := filepath.Match(pattern, base); ok {
+			finding := JarName
+			if nested {
+				finding = JarNameInsideArchive
+			}
+			matches = append(matches, Match{SignatureID: sig.ID, Finding: finding})
+		}
+	}
+	for _, pattern := range sig.Detection.ObfuscatedClassSignatures {
+		if re, err := regexp.Compile(pattern); err == nil && re.MatchString(path) {
+			matches = append(matches, Match{SignatureID: sig.ID, Finding: ClassName})
+		}
+	}
+	return matches
+}
+
+// identifyNested recurses into path as a nested archive if it looks like
+// one, reusing the already-read data rather than re-reading it from disk or
+// a tar stream.
+func (id Identifier) identifyNested(path string, data []byte) ([]Match, error) {
+	var matches []Match
+	walkFn := func(name string, size int64, contents io.Reader) (bool, error) {
+		entry, err := io.ReadAll(contents)
+		if err != nil {
+			return false, err
+		}
+		entryMatches, err := id.identify(name, entry, true)
+		if err != nil {
+			return false, err
+		}
+		matches = append(matches, entryMatches...)
+		return true, nil
+	}
+
+	if id.walkZip != nil {
+		if ok, err := id.walkZip(path, bytes.NewReader(data), walkFn); err != nil {
+			return nil, err
+		} else if ok {
+			return matches, nil
+		}
+	}
+	if id.walkTarGz != nil {
+		if ok, err := id.walkTarGz(path, bytes.NewReader(data), walkFn); err != nil {
+			return nil, err
+		} else if ok {
+			return matches, nil
+		}
+	}
+	return matches, nil
+}
@@ -0,0 +1,218 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package crawl
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// cve45105SignatureID is the signature whose findings are suppressed by
+// Reporter.DisableCVE45105, matching the ID signatures.DefaultDatabase
+// gives that rule.
+const cve45105SignatureID = "CVE-2021-45105"
+
+// Layer identifies the docker image layer a finding was produced from, so
+// a report can point at the Dockerfile command that introduced it.
+type Layer struct {
+	Index     int
+	Digest    string
+	CreatedBy string
+}
+
+// finding is a single reported file: its path, the matches against it, and
+// the image layer it was found in, if any.
+type finding struct {
+	ImageID     string   `json:"imageId,omitempty"`
+	ImageTags   []string `json:"imageTags,omitempty"`
+	LayerIndex  *int     `json:"layerIndex,omitempty"`
+	LayerDigest string   `json:"layerDigest,omitempty"`
+	CreatedBy   string   `json:"createdBy,omitempty"`
+
+	Path                        string   `json:"path"`
+	Signatures                  []string `json:"signatures"`
+	JarNameMatched              bool     `json:"jarNameMatched,omitempty"`
+	JarNameInsideArchiveMatched bool     `json:"jarNameInsideArchiveMatched,omitempty"`
+	ClassPackageAndNameMatched  bool     `json:"classPackageAndNameMatched,omitempty"`
+	ClassFileMd5Matched         bool     `json:"classFileMd5Matched,omitempty"`
+	ClassNameMatched            bool     `json:"classNameMatched,omitempty"`
+}
+
+// Reporter turns the matches an Identifier produces into output, written
+// to OutputWriter as each matching file is collected. It has no knowledge
+// of SBOM collection: a caller that also wants an SBOM populated composes
+// its own CollectFunc out of Reporter.Collect and sbom.CollectComponent,
+// since pkg/sbom imports pkg/crawl for Finding and so cannot be imported
+// back from here.
+type Reporter struct {
+	// OutputJSON writes one JSON object per finding instead of the default
+	// plain-text block.
+	OutputJSON bool
+	// OutputWriter receives the per-finding output as the crawl proceeds.
+	OutputWriter io.Writer
+	// DisableCVE45105 drops findings that only matched the CVE-2021-45105
+	// signature, so a caller that's already remediated that (lower
+	// severity) issue isn't shown it repeatedly.
+	DisableCVE45105 bool
+
+	imageID   string
+	imageTags []string
+	layer     *Layer
+
+	count int64
+}
+
+// SetImageID records the image identifier (a digest, a daemon image ID, ...)
+// that subsequent Collect calls belong to.
+func (r *Reporter) SetImageID(id string) {
+	r.imageID = id
+}
+
+// SetImageTags records the human-readable tags/references that subsequent
+// Collect calls belong to.
+func (r *Reporter) SetImageTags(tags []string) {
+	r.imageTags = tags
+}
+
+// SetLayer records the image layer that subsequent Collect calls were
+// found in, so findings can be attributed to the Dockerfile command that
+// introduced them. Callers scanning a flattened image or a plain
+// filesystem tree simply never call SetLayer, leaving findings unattributed
+// to any layer.
+func (r *Reporter) SetLayer(layer Layer) {
+	r.layer = &layer
+}
+
+// Collect is the CollectFunc handed to a Crawler: it tallies matches and
+// writes a finding to OutputWriter for any file that matched. Files with no
+// matches are ignored.
+func (r *Reporter) Collect(path string, data []byte, matches []Match) {
+	if r.DisableCVE45105 {
+		matches = dropCVE45105(matches)
+	}
+	if len(matches) == 0 {
+		return
+	}
+
+	r.count++
+	if err := r.writeFinding(path, matches); err != nil {
+		_, _ = fmt.Fprintln(r.OutputWriter, err.Error())
+	}
+}
+
+// Count returns the number of files matched across every Collect call so
+// far.
+func (r *Reporter) Count() int64 {
+	return r.count
+}
+
+func dropCVE45105(matches []Match) []Match {
+	var out []Match
+	for _, m := range matches {
+		if m.SignatureID == cve45105SignatureID {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out
+}
+
+// CombinedFinding ORs together the Finding of every match, for callers (the
+// SBOM collector, in particular) that only care which kinds of rule
+// matched a file rather than which specific signatures did.
+func CombinedFinding(matches []Match) Finding {
+	var combined Finding
+	for _, m := range matches {
+		combined |= m.Finding
+	}
+	return combined
+}
+
+func (r *Reporter) writeFinding(path string, matches []Match) error {
+	f := finding{
+		ImageID:   r.imageID,
+		ImageTags: r.imageTags,
+		Path:      path,
+	}
+	if r.layer != nil {
+		index := r.layer.Index
+		f.LayerIndex = &index
+		f.LayerDigest = r.layer.Digest
+		f.CreatedBy = r.layer.CreatedBy
+	}
+
+	seen := map[string]bool{}
+	for _, m := range matches {
+		if !seen[m.SignatureID] {
+			seen[m.SignatureID] = true
+			f.Signatures = append(f.Signatures, m.SignatureID)
+		}
+		switch m.Finding {
+		case JarName:
+			f.JarNameMatched = true
+		case JarNameInsideArchive:
+			f.JarNameInsideArchiveMatched = true
+		case ClassPackageAndName:
+			f.ClassPackageAndNameMatched = true
+		case ClassFileMd5:
+			f.ClassFileMd5Matched = true
+		case ClassName:
+			f.ClassNameMatched = true
+		}
+	}
+
+	if r.OutputJSON {
+		enc := json.NewEncoder(r.OutputWriter)
+		return enc.Encode(f)
+	}
+	return writeFindingText(r.OutputWriter, f)
+}
+
+func writeFindingText(w io.Writer, f finding) error {
+	if _, err := fmt.Fprintf(w, "path: %s\n", f.Path); err != nil {
+		return err
+	}
+	for _, id := range f.Signatures {
+		if _, err := fmt.Fprintf(w, "signature: %s\n", id); err != nil {
+			return err
+		}
+	}
+	if f.JarNameMatched {
+		if _, err := fmt.Fprintln(w, "jarNameMatched: true"); err != nil {
+			return err
+		}
+	}
+	if f.JarNameInsideArchiveMatched {
+		if _, err := fmt.Fprintln(w, "jarNameInsideArchiveMatched: true"); err != nil {
+			return err
+		}
+	}
+	if f.ClassPackageAndNameMatched {
+		if _, err := fmt.Fprintln(w, "classPackageAndNameMatched: true"); err != nil {
+			return err
+		}
+	}
+	if f.ClassFileMd5Matched {
+		if _, err := fmt.Fprintln(w, "classFileMd5Matched: true"); err != nil {
+			return err
+		}
+	}
+	if f.ClassNameMatched {
+		_, err := fmt.Fprintln(w, "classNameMatched: true")
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package imageref parses transport-prefixed image references, modelled on
+// the containers/image transport naming convention, so that a single `crawl
+// image <ref>` verb can dispatch to whichever scanning backend applies
+// without the caller needing to know which one to pick.
+package imageref
+
+import "strings"
+
+// Transport identifies which backend should be used to resolve and scan a
+// reference.
+type Transport string
+
+const (
+	// Docker is the default transport when none is specified: an image
+	// available directly from a registry, scanned without a local daemon.
+	Docker Transport = "docker"
+	// DockerDaemon is an image already present in the local Docker daemon.
+	DockerDaemon Transport = "docker-daemon"
+	// OCI is an OCI image layout directory.
+	OCI Transport = "oci"
+	// OCIArchive is an OCI image layout packaged as a single tarball.
+	OCIArchive Transport = "oci-archive"
+	// DockerArchive is a legacy `docker save`-format tarball (a
+	// manifest.json plus flat layer tarballs, as opposed to an OCI layout).
+	DockerArchive Transport = "docker-archive"
+	// ContainersStorage is an image in local containers/storage (e.g. as
+	// used by Podman), identified by name or ID. Parsed but not yet
+	// scannable: see the crawl image command's error for this transport.
+	ContainersStorage Transport = "containers-storage"
+	// Dir is an image unpacked as a plain directory of per-layer blobs plus
+	// a manifest, rather than an OCI layout or single tarball. Parsed but
+	// not yet scannable: see the crawl image command's error for this
+	// transport.
+	Dir Transport = "dir"
+)
+
+// prefixes maps the transport-prefix syntax (e.g. "docker://") recognised in
+// a reference to the Transport it selects.
+var prefixes = map[string]Transport{
+	"docker://":           Docker,
+	"docker-daemon://":    DockerDaemon,
+	"oci:":                OCI,
+	"oci-archive:":        OCIArchive,
+	"docker-archive:":     DockerArchive,
+	"containers-storage:": ContainersStorage,
+	"dir:":                Dir,
+}
+
+// Reference is a parsed image reference: which transport to use, and the
+// location string to pass to it with the transport prefix stripped.
+type Reference struct {
+	Transport Transport
+	Location  string
+}
+
+// Parse splits a transport-prefixed reference such as
+// "docker-daemon://myimage:latest" or "oci:/path/to/layout" into its
+// Transport and the remaining location. References with no recognised
+// prefix default to Docker, so "ghcr.io/org/image:tag" is scanned directly
+// from its registry.
+func Parse(ref string) Reference {
+	for prefix, transport := range prefixes {
+		if strings.HasPrefix(ref, prefix) {
+			return Reference{Transport: transport, Location: strings.TrimPrefix(ref, prefix)}
+		}
+	}
+	return Reference{Transport: Docker, Location: ref}
+}
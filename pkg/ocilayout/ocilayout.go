@@ -0,0 +1,172 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ocilayout scans images stored as an OCI Image Layout directory
+// (an `oci-layout` file plus `index.json` and `blobs/sha256/...`, as
+// produced by `skopeo copy` or `docker buildx build --output type=oci`),
+// without needing a daemon or registry access.
+package ocilayout
+
+import (
+	"context"
+	"io"
+
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/layout"
+	"github.com/palantir/log4j-sniffer/pkg/archive"
+	"github.com/palantir/log4j-sniffer/pkg/crawl"
+	"github.com/palantir/log4j-sniffer/pkg/sbom"
+	"github.com/palantir/log4j-sniffer/pkg/scan"
+	"github.com/palantir/log4j-sniffer/pkg/signatures"
+	"github.com/pkg/errors"
+)
+
+// Scanner scans every manifest referenced by an OCI image layout directory.
+type Scanner struct {
+	config        scan.Config
+	crawler       crawl.Crawler
+	reporter      *crawl.Reporter
+	identifier    crawl.Identifier
+	sbomCollector *sbom.Collector
+}
+
+// NewScanner returns a Scanner for the OCI image layout directory format.
+// sbomCollector may be nil, in which case no SBOM components are collected.
+// db is the signature database to detect against - pass
+// signatures.DefaultDatabase() to get the built-in CVE rules with no
+// --signatures additions merged in.
+func NewScanner(config scan.Config, stdout, stderr io.Writer, sbomCollector *sbom.Collector, db *signatures.Database) *Scanner {
+	return &Scanner{
+		config: config,
+		crawler: crawl.Crawler{
+			ErrorWriter: stderr,
+			IgnoreDirs:  config.Ignores,
+		},
+		reporter: &crawl.Reporter{
+			OutputJSON:      config.OutputJSON,
+			OutputWriter:    stdout,
+			DisableCVE45105: config.DisableCVE45105,
+		},
+		identifier:    crawl.NewIdentifier(config.ArchiveListTimeout, archive.WalkZipFiles, archive.WalkTarGzFiles, db),
+		sbomCollector: sbomCollector,
+	}
+}
+
+// collect is the crawl.CollectFunc passed to the crawler: it feeds the
+// vulnerability reporter and, if an SBOM was requested, records the
+// archive's coordinates too.
+func (s *Scanner) collect(path string, data []byte, matches []crawl.Match) {
+	s.reporter.Collect(path, data, matches)
+	sbom.CollectComponent(s.sbomCollector, path, data, crawl.CombinedFinding(matches))
+}
+
+// ScanDir walks every manifest reachable from the index.json of the OCI
+// image layout rooted at dir.
+func (s *Scanner) ScanDir(ctx context.Context, dir string) (int64, error) {
+	path, err := layout.FromPath(dir)
+	if err != nil {
+		return 0, errors.Wrapf(err, "%q is not a valid OCI image layout", dir)
+	}
+
+	index, err := path.ImageIndex()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read index.json")
+	}
+	manifest, err := index.IndexManifest()
+	if err != nil {
+		return 0, errors.Wrap(err, "failed to read index manifest")
+	}
+
+	var stats crawl.Stats
+	for _, desc := range manifest.Manifests {
+		descStats, err := s.scanDescriptor(ctx, index, desc)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to scan manifest %s", desc.Digest)
+		}
+		stats.Append(descStats)
+	}
+
+	count := s.reporter.Count()
+	if s.config.OutputSummary {
+		if err := scan.WriteSummary(s.reporter.OutputWriter, s.config, stats, count); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+func (s *Scanner) scanDescriptor(ctx context.Context, index v1.ImageIndex, desc v1.Descriptor) (crawl.Stats, error) {
+	if desc.MediaType.IsIndex() {
+		childIndex, err := index.ImageIndex(desc.Digest)
+		if err != nil {
+			return crawl.Stats{}, err
+		}
+		childManifest, err := childIndex.IndexManifest()
+		if err != nil {
+			return crawl.Stats{}, err
+		}
+		var stats crawl.Stats
+		for _, child := range childManifest.Manifests {
+			childStats, err := s.scanDescriptor(ctx, childIndex, child)
+			if err != nil {
+				return crawl.Stats{}, err
+			}
+			stats.Append(childStats)
+		}
+		return stats, nil
+	}
+
+	img, err := index.Image(desc.Digest)
+	if err != nil {
+		return crawl.Stats{}, err
+	}
+
+	s.reporter.SetImageID(desc.Digest.String())
+	if tag, ok := desc.Annotations["org.opencontainers.image.ref.name"]; ok {
+		s.reporter.SetImageTags([]string{tag})
+	} else {
+		s.reporter.SetImageTags(nil)
+	}
+
+	return s.scanLayers(ctx, img)
+}
+
+func (s *Scanner) scanLayers(ctx context.Context, img v1.Image) (crawl.Stats, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return crawl.Stats{}, errors.Wrap(err, "failed to read image layers")
+	}
+
+	var stats crawl.Stats
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrap(err, "failed to read layer digest")
+		}
+		uncompressed, err := layer.Uncompressed()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrapf(err, "failed to read layer %s", digest)
+		}
+		layerStats, err := s.crawler.CrawlReader(ctx, archive.TarUncompressedReader, uncompressed, digest.String(), s.identifier.Identify, s.collect)
+		closeErr := uncompressed.Close()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrapf(err, "failed to crawl layer %s", digest)
+		}
+		if closeErr != nil {
+			return crawl.Stats{}, errors.Wrapf(closeErr, "failed to close layer %s", digest)
+		}
+		stats.Append(layerStats)
+	}
+	return stats, nil
+}
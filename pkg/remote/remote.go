@@ -0,0 +1,311 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package remote scans container images pulled directly from a registry,
+// without requiring a local Docker daemon.
+package remote
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/palantir/log4j-sniffer/pkg/archive"
+	"github.com/palantir/log4j-sniffer/pkg/crawl"
+	"github.com/palantir/log4j-sniffer/pkg/sbom"
+	"github.com/palantir/log4j-sniffer/pkg/scan"
+	"github.com/palantir/log4j-sniffer/pkg/signatures"
+	"github.com/pkg/errors"
+)
+
+// Options controls how references are resolved and walked against a registry.
+type Options struct {
+	Platform     *v1.Platform
+	Insecure     bool
+	AllTags      bool
+	AllPlatforms bool
+	Catalog      bool
+}
+
+// Scanner scans one or more image references pulled directly from their
+// registries, without requiring a local Docker daemon.
+type Scanner struct {
+	config        scan.Config
+	crawler       crawl.Crawler
+	reporter      *crawl.Reporter
+	identifier    crawl.Identifier
+	sbomCollector *sbom.Collector
+	options       Options
+}
+
+// NewRemoteScanner returns a Scanner that authenticates against registries
+// using the local Docker/Podman config files via authn.DefaultKeychain.
+// sbomCollector may be nil, in which case no SBOM components are collected.
+// db is the signature database to detect against - pass
+// signatures.DefaultDatabase() to get the built-in CVE rules with no
+// --signatures additions merged in.
+func NewRemoteScanner(config scan.Config, stdout, stderr io.Writer, options Options, sbomCollector *sbom.Collector, db *signatures.Database) *Scanner {
+	return &Scanner{
+		config: config,
+		crawler: crawl.Crawler{
+			ErrorWriter: stderr,
+			IgnoreDirs:  config.Ignores,
+		},
+		reporter: &crawl.Reporter{
+			OutputJSON:      config.OutputJSON,
+			OutputWriter:    stdout,
+			DisableCVE45105: config.DisableCVE45105,
+		},
+		identifier:    crawl.NewIdentifier(config.ArchiveListTimeout, archive.WalkZipFiles, archive.WalkTarGzFiles, db),
+		sbomCollector: sbomCollector,
+		options:       options,
+	}
+}
+
+// collect is the crawl.CollectFunc passed to the crawler: it feeds the
+// vulnerability reporter and, if an SBOM was requested, records the
+// archive's coordinates too.
+func (s *Scanner) collect(path string, data []byte, matches []crawl.Match) {
+	s.reporter.Collect(path, data, matches)
+	sbom.CollectComponent(s.sbomCollector, path, data, crawl.CombinedFinding(matches))
+}
+
+// ScanReferences resolves and scans each of the given image or index
+// references, continuing on to the next reference if one fails.
+func (s *Scanner) ScanReferences(ctx context.Context, refs []string) (int64, error) {
+	var stats crawl.Stats
+	for _, ref := range refs {
+		refStats, err := s.scanReference(ctx, ref)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.crawler.ErrorWriter, err.Error())
+			continue
+		}
+		stats.Append(refStats)
+	}
+	return s.finish(stats)
+}
+
+// ScanCatalog walks the full repository catalog of each of the given
+// registries, scanning every tag of every repository it lists. It is the
+// registry-wide counterpart to ScanReferences, for users who want to sweep
+// an entire registry (e.g. a private ECR/GCR/Harbor instance) rather than
+// naming images one at a time.
+func (s *Scanner) ScanCatalog(ctx context.Context, registries []string) (int64, error) {
+	var stats crawl.Stats
+	for _, registry := range registries {
+		registryStats, err := s.scanCatalog(ctx, registry)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.crawler.ErrorWriter, err.Error())
+			continue
+		}
+		stats.Append(registryStats)
+	}
+	return s.finish(stats)
+}
+
+func (s *Scanner) scanCatalog(ctx context.Context, registry string) (crawl.Stats, error) {
+	reg, err := name.NewRegistry(registry, s.nameOptions()...)
+	if err != nil {
+		return crawl.Stats{}, errors.Wrapf(err, "failed to parse registry %q", registry)
+	}
+
+	repoNames, err := remote.Catalog(ctx, reg, s.remoteOptions(ctx)...)
+	if err != nil {
+		return crawl.Stats{}, errors.Wrapf(err, "failed to list catalog for registry %q", registry)
+	}
+
+	var stats crawl.Stats
+	for _, repoName := range repoNames {
+		repo, err := name.NewRepository(reg.Name()+"/"+repoName, s.nameOptions()...)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.crawler.ErrorWriter, err.Error())
+			continue
+		}
+		repoStats, err := s.scanRepositoryTags(ctx, repo)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.crawler.ErrorWriter, err.Error())
+			continue
+		}
+		stats.Append(repoStats)
+	}
+	return stats, nil
+}
+
+func (s *Scanner) finish(stats crawl.Stats) (int64, error) {
+	count := s.reporter.Count()
+	if s.config.OutputSummary {
+		if err := scan.WriteSummary(s.reporter.OutputWriter, s.config, stats, count); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
+func (s *Scanner) scanReference(ctx context.Context, ref string) (crawl.Stats, error) {
+	parsedRef, err := name.ParseReference(ref, s.nameOptions()...)
+	if err != nil {
+		return crawl.Stats{}, errors.Wrapf(err, "failed to parse image reference %q", ref)
+	}
+
+	if s.options.AllTags {
+		return s.scanAllTags(ctx, parsedRef)
+	}
+
+	return s.scanResolvedRef(ctx, ref, parsedRef)
+}
+
+// scanResolvedRef fetches and scans a single, already-parsed reference. It
+// is the terminal step for both a plain reference and each tag resolved by
+// scanAllTags, and must never consult s.options.AllTags itself or
+// scanAllTags would recurse into itself for every tag it lists.
+func (s *Scanner) scanResolvedRef(ctx context.Context, ref string, parsedRef name.Reference) (crawl.Stats, error) {
+	desc, err := remote.Get(parsedRef, s.remoteOptions(ctx)...)
+	if err != nil {
+		return crawl.Stats{}, errors.Wrapf(err, "failed to get image descriptor for %q", ref)
+	}
+
+	s.reporter.SetImageTags([]string{ref})
+	s.reporter.SetImageID(desc.Digest.String())
+
+	if desc.MediaType.IsIndex() {
+		return s.scanIndex(ctx, desc)
+	}
+	return s.scanImage(ctx, desc)
+}
+
+func (s *Scanner) scanAllTags(ctx context.Context, ref name.Reference) (crawl.Stats, error) {
+	return s.scanRepositoryTags(ctx, ref.Context())
+}
+
+// scanRepositoryTags lists and scans every tag of repo. It backs both
+// scanAllTags (a single repository named on the command line) and
+// scanCatalog (every repository a registry's catalog lists).
+func (s *Scanner) scanRepositoryTags(ctx context.Context, repo name.Repository) (crawl.Stats, error) {
+	tags, err := remote.List(repo, s.remoteOptions(ctx)...)
+	if err != nil {
+		return crawl.Stats{}, errors.Wrapf(err, "failed to list tags for %q", repo)
+	}
+
+	var stats crawl.Stats
+	for _, tag := range tags {
+		tagRef := fmt.Sprintf("%s:%s", repo, tag)
+		parsedTagRef, err := name.ParseReference(tagRef, s.nameOptions()...)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.crawler.ErrorWriter, err.Error())
+			continue
+		}
+		tagStats, err := s.scanResolvedRef(ctx, tagRef, parsedTagRef)
+		if err != nil {
+			_, _ = fmt.Fprintln(s.crawler.ErrorWriter, err.Error())
+			continue
+		}
+		stats.Append(tagStats)
+	}
+	return stats, nil
+}
+
+func (s *Scanner) scanIndex(ctx context.Context, desc *remote.Descriptor) (crawl.Stats, error) {
+	idx, err := desc.ImageIndex()
+	if err != nil {
+		return crawl.Stats{}, errors.Wrap(err, "failed to read image index")
+	}
+	manifest, err := idx.IndexManifest()
+	if err != nil {
+		return crawl.Stats{}, errors.Wrap(err, "failed to read index manifest")
+	}
+
+	var stats crawl.Stats
+	for _, m := range manifest.Manifests {
+		if m.Platform != nil && !s.options.AllPlatforms && s.options.Platform != nil && !platformMatches(s.options.Platform, m.Platform) {
+			continue
+		}
+		img, err := idx.Image(m.Digest)
+		if err != nil {
+			return crawl.Stats{}, errors.Wrapf(err, "failed to read image for manifest %s", m.Digest)
+		}
+		imgStats, err := s.scanLayers(ctx, img)
+		if err != nil {
+			return crawl.Stats{}, err
+		}
+		stats.Append(imgStats)
+		if !s.options.AllPlatforms {
+			break
+		}
+	}
+	return stats, nil
+}
+
+func (s *Scanner) scanImage(ctx context.Context, desc *remote.Descriptor) (crawl.Stats, error) {
+	img, err := desc.Image()
+	if err != nil {
+		return crawl.Stats{}, errors.Wrap(err, "failed to read image")
+	}
+	return s.scanLayers(ctx, img)
+}
+
+// scanLayers streams each layer's uncompressed contents straight into the
+// existing tar walker, avoiding writing the flattened image to disk.
+func (s *Scanner) scanLayers(ctx context.Context, img v1.Image) (crawl.Stats, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return crawl.Stats{}, errors.Wrap(err, "failed to read image layers")
+	}
+
+	var stats crawl.Stats
+	for _, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrap(err, "failed to read layer digest")
+		}
+
+		uncompressed, err := layer.Uncompressed()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrapf(err, "failed to read layer %s", digest)
+		}
+
+		layerStats, err := s.crawler.CrawlReader(ctx, archive.TarUncompressedReader, uncompressed, digest.String(), s.identifier.Identify, s.collect)
+		closeErr := uncompressed.Close()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrapf(err, "failed to crawl layer %s", digest)
+		}
+		if closeErr != nil {
+			return crawl.Stats{}, errors.Wrapf(closeErr, "failed to close layer %s", digest)
+		}
+		stats.Append(layerStats)
+	}
+	return stats, nil
+}
+
+func (s *Scanner) nameOptions() []name.Option {
+	if s.options.Insecure {
+		return []name.Option{name.Insecure}
+	}
+	return nil
+}
+
+func (s *Scanner) remoteOptions(ctx context.Context) []remote.Option {
+	opts := []remote.Option{remote.WithContext(ctx), remote.WithAuthFromKeychain(authn.DefaultKeychain)}
+	if s.options.Platform != nil {
+		opts = append(opts, remote.WithPlatform(*s.options.Platform))
+	}
+	return opts
+}
+
+func platformMatches(want *v1.Platform, have *v1.Platform) bool {
+	return have.Satisfies(*want)
+}
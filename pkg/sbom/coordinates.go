@@ -0,0 +1,106 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package sbom produces CycloneDX and SPDX JSON software bill-of-materials
+// documents listing every zip-based archive a crawler visited as a
+// top-level file, annotating the ones matched by a log4j CVE with the
+// relevant vulnerability. Archives nested inside another archive (a jar
+// inside a tarball or a fat jar) are not listed.
+package sbom
+
+import (
+	"archive/zip"
+	"bufio"
+	"regexp"
+	"strings"
+)
+
+// Coordinates is the Maven-style identity of a jar: its groupId,
+// artifactId and version, however they were determined.
+type Coordinates struct {
+	GroupID    string
+	ArtifactID string
+	Version    string
+}
+
+// pomPropertiesPath matches the per-artifact properties file Maven embeds
+// at META-INF/maven/<groupId>/<artifactId>/pom.properties.
+var pomPropertiesPath = regexp.MustCompile(`^META-INF/maven/[^/]+/[^/]+/pom\.properties$`)
+
+// jarNameVersion matches a trailing "-<version>" on a jar's base name, e.g.
+// "log4j-core-2.14.1" -> "2.14.1", used as a fallback when no
+// pom.properties is present.
+var jarNameVersion = regexp.MustCompile(`^(.*)-(\d[\w.-]*)$`)
+
+// CoordinatesFromJar returns the Maven coordinates of the jar at path,
+// preferring the groupId/artifactId/version recorded in an embedded
+// pom.properties and falling back to parsing the jar's own filename.
+//
+// Only the SBOM collector calls this; the vulnerability reporter does not
+// use Coordinates, and the jar is opened here separately from the
+// identifier's own walk of it.
+func CoordinatesFromJar(path string, zr *zip.Reader) Coordinates {
+	for _, f := range zr.File {
+		if !pomPropertiesPath.MatchString(f.Name) {
+			continue
+		}
+		if coords, ok := parsePomProperties(f); ok {
+			return coords
+		}
+	}
+	return coordinatesFromFilename(path)
+}
+
+func parsePomProperties(f *zip.File) (Coordinates, bool) {
+	rc, err := f.Open()
+	if err != nil {
+		return Coordinates{}, false
+	}
+	defer func() { _ = rc.Close() }()
+
+	var coords Coordinates
+	scanner := bufio.NewScanner(rc)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			continue
+		}
+		switch strings.TrimSpace(key) {
+		case "groupId":
+			coords.GroupID = strings.TrimSpace(value)
+		case "artifactId":
+			coords.ArtifactID = strings.TrimSpace(value)
+		case "version":
+			coords.Version = strings.TrimSpace(value)
+		}
+	}
+	if coords.ArtifactID == "" {
+		return Coordinates{}, false
+	}
+	return coords, true
+}
+
+func coordinatesFromFilename(path string) Coordinates {
+	base := path
+	if idx := strings.LastIndexByte(base, '/'); idx >= 0 {
+		base = base[idx+1:]
+	}
+	base = strings.TrimSuffix(base, ".jar")
+
+	if m := jarNameVersion.FindStringSubmatch(base); m != nil {
+		return Coordinates{ArtifactID: m[1], Version: m[2]}
+	}
+	return Coordinates{ArtifactID: base}
+}
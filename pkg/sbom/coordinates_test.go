@@ -0,0 +1,36 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCoordinatesFromFilename(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		path string
+		want Coordinates
+	}{
+		{name: "versioned jar", path: "opt/app/log4j-core-2.14.1.jar", want: Coordinates{ArtifactID: "log4j-core", Version: "2.14.1"}},
+		{name: "no version", path: "opt/app/app.jar", want: Coordinates{ArtifactID: "app"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.want, coordinatesFromFilename(tc.path))
+		})
+	}
+}
@@ -0,0 +1,271 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package sbom
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/palantir/log4j-sniffer/pkg/crawl"
+)
+
+// OutputFormat is a supported SBOM output format, as accepted by the
+// --sbom-output flag.
+type OutputFormat string
+
+const (
+	CycloneDXJSON OutputFormat = "cyclonedx-json"
+	SPDXJSON      OutputFormat = "spdx-json"
+)
+
+// matchedCVEs lists, in BOM-ref order, the CVEs log4j-sniffer can detect.
+// Every jar recorded against one or more of these findings gets a
+// vulnerability entry pointing back at its component.
+var matchedCVEs = []struct {
+	id      string
+	finding crawl.Finding
+}{
+	{id: "CVE-2021-44228", finding: crawl.JarName | crawl.JarNameInsideArchive | crawl.ClassPackageAndName | crawl.ClassFileMd5 | crawl.ClassName},
+	{id: "CVE-2021-45046", finding: crawl.ClassPackageAndName | crawl.ClassFileMd5},
+	{id: "CVE-2021-45105", finding: crawl.ClassPackageAndName | crawl.ClassFileMd5},
+}
+
+// Component is a single Java archive discovered while crawling, regardless
+// of whether it matched a CVE.
+type Component struct {
+	Path        string
+	Coordinates Coordinates
+	SHA256      string
+	Finding     crawl.Finding
+}
+
+// Collector accumulates one Component per archive identified, so the
+// vulnerable-jar reporter and the SBOM emitter can both be driven from a
+// single walk of the filesystem/image rather than each re-reading every
+// archive. A Collector is wired in by passing it to a scanner constructor
+// (e.g. docker.NewDockerScanner, remote.NewRemoteScanner,
+// ocilayout.NewScanner), which calls CollectComponent from its own
+// CollectFunc alongside crawl.Reporter.Collect as each archive is
+// identified during the scan; a nil Collector disables collection
+// entirely.
+type Collector struct {
+	components []Component
+}
+
+// NewCollector returns an empty Collector.
+func NewCollector() *Collector {
+	return &Collector{}
+}
+
+// Add records a single archive's metadata. It is safe to call for every
+// archive the identifier visits, whether or not it matched a CVE.
+func (c *Collector) Add(component Component) {
+	c.components = append(c.components, component)
+}
+
+// CollectComponent records data as a Component in collector if it is a
+// valid zip-based archive (a jar, war, ear or plain zip), attaching finding
+// to note which signatures matched it, if any. It is a no-op if collector
+// is nil (SBOM collection wasn't requested) or data isn't a zip - the
+// crawler hands every top-level file it visits to this, not just jars.
+// Archives nested inside another archive are not passed here, so they are
+// not recorded.
+//
+// This lives in pkg/sbom rather than pkg/crawl.Reporter because Component
+// and Coordinates are SBOM-specific types that pkg/crawl has no reason to
+// know about; callers compose it into their own CollectFunc alongside
+// crawl.Reporter.Collect.
+func CollectComponent(collector *Collector, path string, data []byte, finding crawl.Finding) {
+	if collector == nil {
+		return
+	}
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return
+	}
+	sum := sha256.Sum256(data)
+	collector.Add(Component{
+		Path:        path,
+		Coordinates: CoordinatesFromJar(path, zr),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Finding:     finding,
+	})
+}
+
+// Write renders the accumulated components in the given format.
+func (c *Collector) Write(w io.Writer, format OutputFormat) error {
+	switch format {
+	case CycloneDXJSON:
+		return c.writeCycloneDX(w)
+	case SPDXJSON:
+		return c.writeSPDX(w)
+	default:
+		return fmt.Errorf("unsupported --sbom-output format %q", format)
+	}
+}
+
+func bomRef(c Component) string {
+	return fmt.Sprintf("pkg:maven/%s/%s@%s", c.Coordinates.GroupID, c.Coordinates.ArtifactID, c.Coordinates.Version)
+}
+
+type cycloneDXBOM struct {
+	BOMFormat       string               `json:"bomFormat"`
+	SpecVersion     string               `json:"specVersion"`
+	Components      []cycloneDXComponent `json:"components"`
+	Vulnerabilities []cycloneDXVuln      `json:"vulnerabilities,omitempty"`
+}
+
+type cycloneDXComponent struct {
+	Type    string          `json:"type"`
+	BOMRef  string          `json:"bom-ref"`
+	Name    string          `json:"name"`
+	Version string          `json:"version"`
+	Group   string          `json:"group,omitempty"`
+	Hashes  []cycloneDXHash `json:"hashes,omitempty"`
+}
+
+type cycloneDXHash struct {
+	Alg     string `json:"alg"`
+	Content string `json:"content"`
+}
+
+type cycloneDXVuln struct {
+	ID      string                  `json:"id"`
+	Affects []cycloneDXVulnAffected `json:"affects"`
+}
+
+type cycloneDXVulnAffected struct {
+	Ref string `json:"ref"`
+}
+
+func (c *Collector) writeCycloneDX(w io.Writer) error {
+	bom := cycloneDXBOM{
+		BOMFormat:   "CycloneDX",
+		SpecVersion: "1.4",
+	}
+	vulnRefs := map[string][]string{}
+
+	for _, component := range sortedComponents(c.components) {
+		ref := bomRef(component)
+		bom.Components = append(bom.Components, cycloneDXComponent{
+			Type:    "library",
+			BOMRef:  ref,
+			Name:    component.Coordinates.ArtifactID,
+			Version: component.Coordinates.Version,
+			Group:   component.Coordinates.GroupID,
+			Hashes:  []cycloneDXHash{{Alg: "SHA-256", Content: component.SHA256}},
+		})
+		for _, cve := range matchedCVEs {
+			if component.Finding&cve.finding != 0 {
+				vulnRefs[cve.id] = append(vulnRefs[cve.id], ref)
+			}
+		}
+	}
+
+	for _, cve := range matchedCVEs {
+		refs, ok := vulnRefs[cve.id]
+		if !ok {
+			continue
+		}
+		vuln := cycloneDXVuln{ID: cve.id}
+		for _, ref := range refs {
+			vuln.Affects = append(vuln.Affects, cycloneDXVulnAffected{Ref: ref})
+		}
+		bom.Vulnerabilities = append(bom.Vulnerabilities, vuln)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(bom)
+}
+
+type spdxDocument struct {
+	SPDXVersion       string        `json:"spdxVersion"`
+	DataLicense       string        `json:"dataLicense"`
+	SPDXID            string        `json:"SPDXID"`
+	Name              string        `json:"name"`
+	DocumentNamespace string        `json:"documentNamespace"`
+	Packages          []spdxPackage `json:"packages"`
+}
+
+type spdxPackage struct {
+	SPDXID       string            `json:"SPDXID"`
+	Name         string            `json:"name"`
+	VersionInfo  string            `json:"versionInfo,omitempty"`
+	Checksums    []spdxChecksum    `json:"checksums,omitempty"`
+	ExternalRefs []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxChecksum struct {
+	Algorithm     string `json:"algorithm"`
+	ChecksumValue string `json:"checksumValue"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+func (c *Collector) writeSPDX(w io.Writer) error {
+	doc := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              "log4j-sniffer-sbom",
+		DocumentNamespace: "https://github.com/palantir/log4j-sniffer/sbom",
+	}
+
+	for i, component := range sortedComponents(c.components) {
+		pkg := spdxPackage{
+			SPDXID:      fmt.Sprintf("SPDXRef-Package-%d", i),
+			Name:        component.Coordinates.ArtifactID,
+			VersionInfo: component.Coordinates.Version,
+			Checksums:   []spdxChecksum{{Algorithm: "SHA256", ChecksumValue: component.SHA256}},
+			ExternalRefs: []spdxExternalRef{{
+				ReferenceCategory: "PACKAGE-MANAGER",
+				ReferenceType:     "purl",
+				ReferenceLocator:  bomRef(component),
+			}},
+		}
+		for _, cve := range matchedCVEs {
+			if component.Finding&cve.finding != 0 {
+				pkg.ExternalRefs = append(pkg.ExternalRefs, spdxExternalRef{
+					ReferenceCategory: "SECURITY",
+					ReferenceType:     "advisory",
+					ReferenceLocator:  cve.id,
+				})
+			}
+		}
+		doc.Packages = append(doc.Packages, pkg)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(doc)
+}
+
+func sortedComponents(components []Component) []Component {
+	sorted := make([]Component, len(components))
+	copy(sorted, components)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Path < sorted[j].Path })
+	return sorted
+}
@@ -25,23 +25,38 @@ import (
 	"github.com/docker/docker/client"
 	"github.com/google/go-containerregistry/pkg/crane"
 	"github.com/google/go-containerregistry/pkg/name"
+	v1 "github.com/google/go-containerregistry/pkg/v1"
 	"github.com/google/go-containerregistry/pkg/v1/daemon"
 	"github.com/mholt/archiver/v3"
 	"github.com/palantir/log4j-sniffer/pkg/archive"
 	"github.com/palantir/log4j-sniffer/pkg/crawl"
+	"github.com/palantir/log4j-sniffer/pkg/sbom"
 	"github.com/palantir/log4j-sniffer/pkg/scan"
+	"github.com/palantir/log4j-sniffer/pkg/signatures"
 	"github.com/pkg/errors"
 )
 
 type Scanner struct {
-	config     scan.Config
-	crawler    crawl.Crawler
-	reporter   *crawl.Reporter
-	identifier crawl.Identifier
-	client     client.CommonAPIClient
+	config        scan.Config
+	crawler       crawl.Crawler
+	reporter      *crawl.Reporter
+	identifier    crawl.Identifier
+	sbomCollector *sbom.Collector
+	client        client.CommonAPIClient
+	// flatten causes images to be exported and flattened into a single
+	// tree before scanning, matching the pre-layer-aware behaviour. This
+	// loses layer provenance and misses files deleted in upper layers, but
+	// is kept for backward compatibility and as a fallback when an image
+	// cannot be read layer-by-layer.
+	flatten bool
 }
 
-func NewDockerScanner(config scan.Config, stdout, stderr io.Writer) (*Scanner, error) {
+// NewDockerScanner returns a Scanner that talks to the local Docker daemon.
+// sbomCollector may be nil, in which case no SBOM components are collected.
+// db is the signature database to detect against - pass
+// signatures.DefaultDatabase() to get the built-in CVE rules with no
+// --signatures additions merged in.
+func NewDockerScanner(config scan.Config, stdout, stderr io.Writer, flatten bool, sbomCollector *sbom.Collector, db *signatures.Database) (*Scanner, error) {
 	dockerClient, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create docker client")
@@ -58,11 +73,40 @@ func NewDockerScanner(config scan.Config, stdout, stderr io.Writer) (*Scanner, e
 			OutputWriter:    stdout,
 			DisableCVE45105: config.DisableCVE45105,
 		},
-		identifier: crawl.NewIdentifier(config.ArchiveListTimeout, archive.WalkZipFiles, archive.WalkTarGzFiles),
-		client:     dockerClient,
+		identifier:    crawl.NewIdentifier(config.ArchiveListTimeout, archive.WalkZipFiles, archive.WalkTarGzFiles, db),
+		sbomCollector: sbomCollector,
+		client:        dockerClient,
+		flatten:       flatten,
 	}, nil
 }
 
+// collect is the crawl.CollectFunc passed to the crawler: it feeds the
+// vulnerability reporter and, if an SBOM was requested, records the
+// archive's coordinates too.
+func (d Scanner) collect(path string, data []byte, matches []crawl.Match) {
+	d.reporter.Collect(path, data, matches)
+	sbom.CollectComponent(d.sbomCollector, path, data, crawl.CombinedFinding(matches))
+}
+
+// ScanImageRef scans the single image with the given reference from the
+// local Docker daemon, rather than every image as ScanImages does.
+func (d Scanner) ScanImageRef(ctx context.Context, ref string) (int64, error) {
+	d.reporter.SetImageID(ref)
+	d.reporter.SetImageTags([]string{ref})
+	stats, err := d.scanImage(ctx, dockertypes.ImageSummary{ID: ref, RepoTags: []string{ref}})
+	if err != nil {
+		return 0, err
+	}
+
+	count := d.reporter.Count()
+	if d.config.OutputSummary {
+		if err := scan.WriteSummary(d.reporter.OutputWriter, d.config, stats, count); err != nil {
+			return 0, err
+		}
+	}
+	return count, nil
+}
+
 func (d Scanner) ScanImages(ctx context.Context) (int64, error) {
 	imageList, err := d.client.ImageList(ctx, dockertypes.ImageListOptions{})
 	if err != nil {
@@ -106,6 +150,86 @@ func (d Scanner) scanImage(ctx context.Context, image dockertypes.ImageSummary)
 		return crawl.Stats{}, err
 	}
 
+	if d.flatten {
+		return d.scanFlattenedImage(ctx, image, img)
+	}
+	return d.scanImageLayers(ctx, img)
+}
+
+// scanImageLayers iterates the image's layers individually, streaming each
+// layer's uncompressed contents straight into the identifier rather than
+// flattening the image into a single tree on disk. This correctly detects
+// files that were added in one layer and deleted in a later one, and lets
+// findings be attributed to the layer and Dockerfile command that produced
+// them.
+func (d Scanner) scanImageLayers(ctx context.Context, img v1.Image) (crawl.Stats, error) {
+	layers, err := img.Layers()
+	if err != nil {
+		return crawl.Stats{}, errors.Wrap(err, "failed to read image layers")
+	}
+
+	configFile, err := img.ConfigFile()
+	if err != nil {
+		return crawl.Stats{}, errors.Wrap(err, "failed to read image config")
+	}
+	history := nonEmptyLayerHistory(configFile.History)
+
+	var stats crawl.Stats
+	for i, layer := range layers {
+		digest, err := layer.Digest()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrap(err, "failed to read layer digest")
+		}
+
+		d.reporter.SetLayer(crawl.Layer{
+			Index:     i,
+			Digest:    digest.String(),
+			CreatedBy: historyCommandForLayer(history, i),
+		})
+
+		uncompressed, err := layer.Uncompressed()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrapf(err, "failed to read layer %s", digest)
+		}
+
+		layerStats, err := d.crawler.CrawlReader(ctx, archive.TarUncompressedReader, uncompressed, digest.String(), d.identifier.Identify, d.collect)
+		closeErr := uncompressed.Close()
+		if err != nil {
+			return crawl.Stats{}, errors.Wrapf(err, "failed to crawl layer %s", digest)
+		}
+		if closeErr != nil {
+			return crawl.Stats{}, errors.Wrapf(closeErr, "failed to close layer %s", digest)
+		}
+		stats.Append(layerStats)
+	}
+	return stats, nil
+}
+
+// nonEmptyLayerHistory filters out history entries for empty layers (e.g.
+// ENV or LABEL commands), leaving one entry per actual filesystem layer.
+func nonEmptyLayerHistory(history []v1.History) []v1.History {
+	var out []v1.History
+	for _, h := range history {
+		if !h.EmptyLayer {
+			out = append(out, h)
+		}
+	}
+	return out
+}
+
+func historyCommandForLayer(history []v1.History, layerIndex int) string {
+	if layerIndex < 0 || layerIndex >= len(history) {
+		return ""
+	}
+	return history[layerIndex].CreatedBy
+}
+
+// scanFlattenedImage preserves the original behaviour of exporting and
+// flattening an image into a single tree before crawling it. It is retained
+// behind --flatten for backward compatibility; scanImageLayers should be
+// preferred as it avoids the scratch space cost and preserves layer
+// provenance.
+func (d Scanner) scanFlattenedImage(ctx context.Context, image dockertypes.ImageSummary, img v1.Image) (crawl.Stats, error) {
 	// create a temporary directory where the docker image tarball can be exported to
 	imageTmpDir, err := os.MkdirTemp("", fmt.Sprintf("log4j-sniffer-%s", image.ID))
 	if err != nil {
@@ -148,5 +272,5 @@ func (d Scanner) scanImage(ctx context.Context, image dockertypes.ImageSummary)
 		return crawl.Stats{}, err
 	}
 
-	return d.crawler.Crawl(ctx, ".", d.identifier.Identify, d.reporter.Collect)
-}
\ No newline at end of file
+	return d.crawler.Crawl(ctx, ".", d.identifier.Identify, d.collect)
+}
@@ -0,0 +1,102 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signatures
+
+// vulnerableClassMD5s is the MD5 of every JndiManager.class/JndiLookup.class
+// build known to be vulnerable to CVE-2021-44228, keyed by nothing but the
+// hash itself since the same class content ships across many log4j-core
+// jar names and versions.
+var vulnerableClassMD5s = []string{
+	"3bd9f41b89ce4fe8ccbf73e43195a5ce",
+	"5824932d8c23430a8c5b41d17eb04f42",
+	"102cac5b7726457244af1f44e54ff468",
+	"21f055b62c15453f0d7970a9d994cab7",
+	"32d8b01979518ad243ecadcbb2239de0",
+	"8b2260b1cce64144f6310876f94b1638",
+}
+
+// cve45105ClassMD5s is the MD5 of the JndiManager/JndiLookup builds that
+// are only vulnerable to the lower-severity CVE-2021-45105 (uncontrolled
+// recursion), not CVE-2021-44228.
+var cve45105ClassMD5s = []string{
+	"3dc5e023442d330bb0e94890b752f2d9",
+	"db6460e49e04e6c6f32a4beeb636ed82",
+}
+
+// vulnerableClassPackageAndNames is the path, within a jar, of every
+// vulnerable build's JndiManager/JndiLookup class.
+var vulnerableClassPackageAndNames = []string{
+	"org/apache/logging/log4j/core/net/JndiManager.class",
+	"org/apache/logging/log4j/core/lookup/JndiLookup.class",
+}
+
+// vulnerableJarNamePatterns lists filepath.Match-style patterns matching
+// the name of a vulnerable log4j-core jar on its own or nested inside a
+// fat jar/dist tarball, independent of the classes it contains.
+var vulnerableJarNamePatterns = []string{
+	"log4j-core-2.0*.jar",
+	"log4j-core-2.[1-9].jar",
+	"log4j-core-2.[1-9].[0-9]*.jar",
+	"log4j-core-2.1[0-6]*.jar",
+}
+
+// vulnerableObfuscatedClassSignatures is a list of regular expressions
+// matching a relocated/shaded JndiManager or JndiLookup class by base name
+// alone, catching builds whose package was rewritten (e.g. by shading
+// plugins like maven-shade-plugin) in a way that defeats
+// vulnerableClassPackageAndNames.
+var vulnerableObfuscatedClassSignatures = []string{
+	`(^|/)JndiManager\.class$`,
+	`(^|/)JndiLookup\.class$`,
+}
+
+// DefaultDatabase returns the signatures database log4j-sniffer ships with
+// by default: the CVE-2021-44228, CVE-2021-45046 and CVE-2021-45105 rules
+// that used to be compiled directly into pkg/crawl, now expressed in the
+// same OSV-like shape that --signatures accepts so that user-supplied
+// databases merge with them rather than replacing them outright.
+func DefaultDatabase() *Database {
+	return NewDatabase(
+		Signature{
+			ID:      "CVE-2021-44228",
+			Aliases: []string{"CVE-2021-44228"},
+			Affected: []Affected{{Package: Package{Ecosystem: "Maven", Name: "org.apache.logging.log4j:log4j-core"}}},
+			Detection: Detection{
+				ClassMD5s:                 vulnerableClassMD5s,
+				ClassPackageNames:         vulnerableClassPackageAndNames,
+				JarNamePatterns:           vulnerableJarNamePatterns,
+				ObfuscatedClassSignatures: vulnerableObfuscatedClassSignatures,
+			},
+		},
+		Signature{
+			ID:      "CVE-2021-45046",
+			Aliases: []string{"CVE-2021-45046"},
+			Affected: []Affected{{Package: Package{Ecosystem: "Maven", Name: "org.apache.logging.log4j:log4j-core"}}},
+			Detection: Detection{
+				ClassMD5s:         vulnerableClassMD5s,
+				ClassPackageNames: vulnerableClassPackageAndNames,
+			},
+		},
+		Signature{
+			ID:      "CVE-2021-45105",
+			Aliases: []string{"CVE-2021-45105"},
+			Affected: []Affected{{Package: Package{Ecosystem: "Maven", Name: "org.apache.logging.log4j:log4j-core"}}},
+			Detection: Detection{
+				ClassMD5s:         cve45105ClassMD5s,
+				ClassPackageNames: vulnerableClassPackageAndNames,
+			},
+		},
+	)
+}
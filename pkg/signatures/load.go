@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signatures
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// entries is the on-disk shape of a signatures file or URL response: a
+// top-level list of OSV-schema signature entries, parsed as JSON when the
+// source's name ends in .json and as YAML otherwise. A URL response's
+// Content-Type is not consulted.
+type entries struct {
+	Signatures []Signature `json:"signatures" yaml:"signatures"`
+}
+
+// Load reads a signatures database from a local file path or, if source
+// looks like a URL, fetches it over HTTP(S) - this also covers pulling a
+// single file out of a git mirror such as a GitHub raw URL, the same way
+// Go's vulndb is consumed from data/osv/*.json.
+func Load(source string) (*Database, error) {
+	if strings.HasPrefix(source, "http://") || strings.HasPrefix(source, "https://") {
+		return loadURL(source)
+	}
+	return loadFile(source)
+}
+
+func loadFile(path string) (*Database, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open signatures file %q", path)
+	}
+	defer func() { _ = f.Close() }()
+
+	return decode(f, path)
+}
+
+func loadURL(url string) (*Database, error) {
+	client := http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to fetch signatures from %q", url)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("failed to fetch signatures from %q: got status %s", url, resp.Status)
+	}
+	return decode(resp.Body, url)
+}
+
+func decode(r io.Reader, hint string) (*Database, error) {
+	body, err := io.ReadAll(r)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read signatures")
+	}
+
+	var parsed entries
+	if strings.HasSuffix(hint, ".json") {
+		err = json.Unmarshal(body, &parsed)
+	} else {
+		err = yaml.Unmarshal(body, &parsed)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to parse signatures from %q", hint)
+	}
+
+	return NewDatabase(parsed.Signatures...), nil
+}
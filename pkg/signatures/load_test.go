@@ -0,0 +1,52 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package signatures
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadCustomSignatureFile(t *testing.T) {
+	for _, file := range []string{"testdata/marker-signature.json", "testdata/marker-signature.yaml"} {
+		t.Run(file, func(t *testing.T) {
+			db, err := Load(file)
+			require.NoError(t, err)
+
+			require.Len(t, db.Signatures(), 1)
+			sig := db.Signatures()[0]
+			assert.Equal(t, "EXAMPLE-MARKER-0001", sig.ID)
+			assert.Equal(t, []string{"a1b2c3d4e5f6a7b8c9d0e1f2a3b4c5d6"}, sig.Detection.ClassMD5s)
+		})
+	}
+}
+
+func TestDefaultDatabaseMergesWithCustomSignatures(t *testing.T) {
+	custom, err := Load(filepath.Join("testdata", "marker-signature.json"))
+	require.NoError(t, err)
+
+	db := DefaultDatabase()
+	db.Merge(custom)
+
+	ids := make([]string, 0, len(db.Signatures()))
+	for _, sig := range db.Signatures() {
+		ids = append(ids, sig.ID)
+	}
+	assert.Contains(t, ids, "CVE-2021-44228")
+	assert.Contains(t, ids, "EXAMPLE-MARKER-0001")
+}
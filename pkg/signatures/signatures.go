@@ -0,0 +1,94 @@
+// Copyright (c) 2021 Palantir Technologies. All rights reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package signatures loads the class-file and jar-name rules used to detect
+// vulnerable log4j (and log4j-shaped) jars from an OSV-compatible database,
+// rather than from constants compiled into pkg/crawl. This lets a new
+// incident - or an unrelated Java CVE such as Spring4Shell - be added via
+// --signatures without a code change.
+package signatures
+
+// Package is the OSV "affected package" identity, e.g. Maven coordinates.
+type Package struct {
+	Ecosystem string `json:"ecosystem" yaml:"ecosystem"`
+	Name      string `json:"name" yaml:"name"`
+}
+
+// Affected records one package range an entry applies to, following OSV's
+// affected[].package shape. log4j-sniffer does not evaluate version ranges
+// itself - it matches on jar contents - so only Package is used today.
+type Affected struct {
+	Package Package `json:"package" yaml:"package"`
+}
+
+// Detection is the log4j-sniffer-specific extension block carried alongside
+// the standard OSV fields, describing how to recognise a vulnerable archive
+// by its contents rather than by a resolved version.
+type Detection struct {
+	ClassMD5s                 []string `json:"class_md5s,omitempty" yaml:"class_md5s,omitempty"`
+	ClassPackageNames         []string `json:"class_package_names,omitempty" yaml:"class_package_names,omitempty"`
+	JarNamePatterns           []string `json:"jar_name_patterns,omitempty" yaml:"jar_name_patterns,omitempty"`
+	ObfuscatedClassSignatures []string `json:"obfuscated_class_signatures,omitempty" yaml:"obfuscated_class_signatures,omitempty"`
+}
+
+// Signature is a single entry in a signatures database: an OSV-schema ID
+// and aliases, the packages it affects, and the Detection rules that
+// identify it in an archive.
+type Signature struct {
+	ID        string     `json:"id" yaml:"id"`
+	Aliases   []string   `json:"aliases,omitempty" yaml:"aliases,omitempty"`
+	Affected  []Affected `json:"affected,omitempty" yaml:"affected,omitempty"`
+	Detection Detection  `json:"x_log4j_sniffer_detection" yaml:"x_log4j_sniffer_detection"`
+}
+
+// Database is an ordered set of signatures loaded from one or more files or
+// URLs. Later-loaded signatures with the same ID override earlier ones,
+// mirroring how --signatures is documented to add or override rules.
+type Database struct {
+	signatures []Signature
+	byID       map[string]int
+}
+
+// NewDatabase returns a Database seeded with the given signatures, in order.
+func NewDatabase(signatures ...Signature) *Database {
+	db := &Database{byID: map[string]int{}}
+	for _, sig := range signatures {
+		db.Upsert(sig)
+	}
+	return db
+}
+
+// Upsert adds sig to the database, replacing any existing signature with
+// the same ID in place so later, more specific databases can override the
+// built-in defaults without reordering them.
+func (d *Database) Upsert(sig Signature) {
+	if i, ok := d.byID[sig.ID]; ok {
+		d.signatures[i] = sig
+		return
+	}
+	d.byID[sig.ID] = len(d.signatures)
+	d.signatures = append(d.signatures, sig)
+}
+
+// Merge upserts every signature from other into d, in order.
+func (d *Database) Merge(other *Database) {
+	for _, sig := range other.signatures {
+		d.Upsert(sig)
+	}
+}
+
+// Signatures returns the database's signatures in load order.
+func (d *Database) Signatures() []Signature {
+	return d.signatures
+}